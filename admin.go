@@ -0,0 +1,203 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type RenameRequest struct {
+	NewTitle string `json:"new_title"`
+}
+
+// AdminAPI guards the /admin/ routes with HTTP Basic Auth and performs
+// mutations that the public API has no way to trigger: deleting a video,
+// renaming one, and re-scanning videos/ for files added out-of-band.
+type AdminAPI struct {
+	Username string
+	Password string
+	Store    Store
+}
+
+// NewAdminAPI requires both credentials to be configured. Without this,
+// an operator who forgets -admin-user/-admin-pass (or ADMIN_USER/
+// ADMIN_PASS) would end up with the admin API open to anyone, since
+// r.BasicAuth() reports ok=true even for an empty user and password.
+func NewAdminAPI(store Store, username, password string) *AdminAPI {
+	if username == "" || password == "" {
+		log.Fatal("admin username and password must be set via -admin-user/-admin-pass or ADMIN_USER/ADMIN_PASS")
+	}
+	return &AdminAPI{Username: username, Password: password, Store: store}
+}
+
+func (a *AdminAPI) authorized(user, pass string) bool {
+	userMatch := subtle.ConstantTimeCompare([]byte(user), []byte(a.Username)) == 1
+	passMatch := subtle.ConstantTimeCompare([]byte(pass), []byte(a.Password)) == 1
+	return userMatch && passMatch
+}
+
+func (a *AdminAPI) basicAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || !a.authorized(user, pass) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="admin"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// videosHandler dispatches DELETE /admin/videos/{title}, POST
+// /admin/videos/{title}/rename and POST /admin/videos/reindex. The
+// standard library mux has no path parameters, so the remainder of the
+// path is split by hand the same way the rest of this codebase avoids
+// pulling in a router.
+func (a *AdminAPI) videosHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/videos/"), "/")
+		if rest == "" {
+			http.Error(w, "Missing video title", http.StatusBadRequest)
+			return
+		}
+
+		parts := strings.Split(rest, "/")
+
+		switch {
+		case len(parts) == 1 && parts[0] == "reindex" && r.Method == http.MethodPost:
+			a.reindexVideos(w, r)
+		case len(parts) == 1 && r.Method == http.MethodDelete:
+			a.deleteVideo(w, r, parts[0])
+		case len(parts) == 2 && parts[1] == "rename" && r.Method == http.MethodPost:
+			a.renameVideo(w, r, parts[0])
+		default:
+			http.Error(w, "Method not supported", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func (a *AdminAPI) deleteVideo(w http.ResponseWriter, r *http.Request, title string) {
+	exists, err := a.Store.ExistsByTitle(title)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		http.Error(w, "Video not found", http.StatusNotFound)
+		return
+	}
+
+	if err := a.Store.DeleteByTitle(title); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if err := a.Store.DeleteVariantsByTitle(title); err != nil {
+		log.Printf("Error clearing variants for %s: %v", title, err)
+	}
+
+	// The DB row is already gone, so file removal is best-effort: a failure
+	// here leaves orphaned files on disk, not a dangling row pointing at
+	// missing files, which is the failure mode this endpoint exists to avoid.
+	if err := os.RemoveAll(filepath.Join("videos", title)); err != nil {
+		log.Printf("Error deleting video files for %s: %v", title, err)
+	}
+
+	user, _, _ := r.BasicAuth()
+	log.Printf("admin %s deleted video %s", user, title)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a *AdminAPI) renameVideo(w http.ResponseWriter, r *http.Request, title string) {
+	var req RenameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.NewTitle == "" {
+		http.Error(w, "Missing new_title in request body", http.StatusBadRequest)
+		return
+	}
+	newTitle, err := sanitizePathComponent(req.NewTitle)
+	if err != nil {
+		http.Error(w, "Invalid new_title", http.StatusBadRequest)
+		return
+	}
+
+	oldPath, err := a.Store.GetPathByTitle(title)
+	if err != nil {
+		if err == ErrNotFound {
+			http.Error(w, "Video not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	exists, err := a.Store.ExistsByTitle(newTitle)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if exists {
+		http.Error(w, "A video with this title already exists", http.StatusConflict)
+		return
+	}
+
+	oldDir := filepath.Join("videos", title)
+	newDir := filepath.Join("videos", newTitle)
+	if _, err := os.Stat(newDir); err == nil {
+		http.Error(w, "A directory for this title already exists", http.StatusConflict)
+		return
+	}
+
+	if err := os.Rename(oldDir, newDir); err != nil {
+		http.Error(w, "Error renaming video directory", http.StatusInternalServerError)
+		return
+	}
+
+	newPath := filepath.Join(newDir, filepath.Base(oldPath))
+	oldFile := filepath.Join(newDir, title+".m3u8")
+	if _, err := os.Stat(oldFile); err == nil {
+		newFile := filepath.Join(newDir, newTitle+".m3u8")
+		if err := os.Rename(oldFile, newFile); err != nil {
+			http.Error(w, "Error renaming playlist file", http.StatusInternalServerError)
+			return
+		}
+		newPath = newFile
+	}
+	newPath = filepath.ToSlash(newPath)
+
+	if err := a.Store.RenameVideo(title, newTitle, newPath); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	oldPrefix := filepath.ToSlash(oldDir) + "/"
+	newPrefix := filepath.ToSlash(newDir) + "/"
+	if err := a.Store.RenameVariantsByTitle(title, newTitle, oldPrefix, newPrefix); err != nil {
+		log.Printf("Error updating variants for %s: %v", title, err)
+	}
+
+	user, _, _ := r.BasicAuth()
+	log.Printf("admin %s renamed video %s to %s", user, title, newTitle)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Video{Title: newTitle, Path: newPath})
+}
+
+func (a *AdminAPI) reindexVideos(w http.ResponseWriter, r *http.Request) {
+	if err := indexVideos(a.Store, "videos"); err != nil {
+		http.Error(w, "Error reindexing videos", http.StatusInternalServerError)
+		return
+	}
+
+	user, _, _ := r.BasicAuth()
+	log.Printf("admin %s reindexed videos", user)
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	gopath "path"
+	"path/filepath"
+	"strings"
+
+	"github.com/grafov/m3u8"
+)
+
+// fetchMasterVariants walks every variant stream (and EXT-X-MEDIA
+// alternate rendition) referenced by a master playlist, downloads its
+// child media playlist and segments into a subdirectory of dir, and
+// records each variant in the variants table so videoLinkHandler keeps
+// returning the single master URL as the entry point.
+func fetchMasterVariants(store Store, master *m3u8.MasterPlaylist, dir, baseURL, name string, concurrency int) error {
+	for _, variant := range master.Variants {
+		if variant == nil || variant.URI == "" {
+			continue
+		}
+
+		if err := fetchVariant(store, variant, dir, baseURL, name, concurrency); err != nil {
+			return err
+		}
+
+		for i, alt := range variant.Alternatives {
+			if alt == nil || alt.URI == "" {
+				continue
+			}
+			if err := fetchAlternative(dir, baseURL, name, alt, i, concurrency); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// variantKey derives a directory name from playlist-supplied attributes.
+// Those attributes come straight from a remote m3u8 fetched by
+// downloadHandler, so they're sanitized before use the same way
+// request-body identifiers are in upload.go.
+func variantKey(variant *m3u8.Variant) string {
+	raw := variant.Resolution
+	if raw == "" {
+		raw = fmt.Sprintf("%d", variant.Bandwidth)
+	}
+	if key, err := sanitizePathComponent(raw); err == nil {
+		return key
+	}
+	return fmt.Sprintf("variant-%d", variant.Bandwidth)
+}
+
+func fetchVariant(store Store, variant *m3u8.Variant, dir, baseURL, name string, concurrency int) error {
+	localDir := filepath.Join(dir, variantKey(variant))
+	if err := os.MkdirAll(localDir, os.ModePerm); err != nil {
+		return fmt.Errorf("creating variant directory %s: %w", localDir, err)
+	}
+
+	remoteDir := remoteDirFor(baseURL, name, variant.URI)
+	playlistOutput := filepath.Join(localDir, gopath.Base(variant.URI))
+	playlistURL := remoteDir + "/" + gopath.Base(variant.URI)
+
+	if err := downloadToFile(playlistURL, playlistOutput); err != nil {
+		return fmt.Errorf("downloading variant playlist %s: %w", variant.URI, err)
+	}
+
+	if err := fetchChildSegments(playlistOutput, localDir, remoteDir, concurrency); err != nil {
+		return fmt.Errorf("fetching segments for variant %s: %w", variant.URI, err)
+	}
+
+	relativePath := filepath.ToSlash(playlistOutput)
+	if err := store.InsertVariant(name, variant.Bandwidth, variant.Resolution, relativePath); err != nil {
+		return fmt.Errorf("recording variant: %w", err)
+	}
+
+	return nil
+}
+
+func fetchAlternative(dir, baseURL, name string, alt *m3u8.Alternative, index, concurrency int) error {
+	raw := alt.GroupId
+	if raw == "" {
+		raw = alt.Name
+	}
+	key, err := sanitizePathComponent(raw)
+	if err != nil {
+		key = fmt.Sprintf("alt-%d", index)
+	}
+	localDir := filepath.Join(dir, key)
+	if err := os.MkdirAll(localDir, os.ModePerm); err != nil {
+		return fmt.Errorf("creating alternative directory %s: %w", localDir, err)
+	}
+
+	remoteDir := remoteDirFor(baseURL, name, alt.URI)
+	output := filepath.Join(localDir, gopath.Base(alt.URI))
+	playlistURL := remoteDir + "/" + gopath.Base(alt.URI)
+
+	if err := downloadToFile(playlistURL, output); err != nil {
+		return fmt.Errorf("downloading alternate rendition %s: %w", alt.URI, err)
+	}
+
+	if gopath.Ext(alt.URI) == ".m3u8" {
+		if err := fetchChildSegments(output, localDir, remoteDir, concurrency); err != nil {
+			return fmt.Errorf("fetching segments for alternate rendition %s: %w", alt.URI, err)
+		}
+	}
+
+	return nil
+}
+
+// remoteDirFor mirrors the base-URL derivation downloadHandler already
+// uses for flat layouts, but also honours a sub-path when a variant's URI
+// is nested relative to the master (e.g. "720p/index.m3u8").
+func remoteDirFor(baseURL, name, uri string) string {
+	remoteDir := baseURL + "/" + name
+	if dir := gopath.Dir(uri); dir != "." {
+		remoteDir = remoteDir + "/" + dir
+	}
+	return remoteDir
+}
+
+func fetchChildSegments(playlistPath, localDir, remoteDir string, concurrency int) error {
+	content, err := ioutil.ReadFile(playlistPath)
+	if err != nil {
+		return err
+	}
+
+	playlist, listType, err := m3u8.DecodeFrom(bufio.NewReader(strings.NewReader(string(content))), true)
+	if err != nil {
+		return err
+	}
+
+	if listType != m3u8.MEDIA {
+		return nil
+	}
+
+	mediaPlaylist := playlist.(*m3u8.MediaPlaylist)
+	var jobs []segmentJob
+	for _, segment := range mediaPlaylist.Segments {
+		if segment == nil || segment.URI == "" {
+			continue
+		}
+
+		segmentURI, err := sanitizeRelativePath(segment.URI)
+		if err != nil {
+			log.Printf("Skipping segment with unsafe URI in %s: %v", remoteDir, err)
+			continue
+		}
+
+		segmentOutput := filepath.Join(localDir, segmentURI)
+		if err := os.MkdirAll(filepath.Dir(segmentOutput), os.ModePerm); err != nil {
+			return err
+		}
+
+		jobs = append(jobs, segmentJob{URL: remoteDir + "/" + segment.URI, Output: segmentOutput})
+	}
+
+	return downloadSegmentsPool(jobs, concurrency, nil)
+}
+
+func downloadToFile(url, output string) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, resp.Body)
+	return err
+}
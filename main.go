@@ -2,7 +2,6 @@ package main
 
 import (
 	"bufio"
-	"database/sql"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -16,7 +15,6 @@ import (
 	"strings"
 
 	"github.com/grafov/m3u8"
-	_ "github.com/mattn/go-sqlite3"
 )
 
 type VideoTitleResponse struct {
@@ -41,18 +39,11 @@ type VideoResponse struct {
 	URL string `json:"url"`
 }
 
-func InitDB() *sql.DB {
-	db, err := sql.Open("sqlite3", "videos.db")
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS videos (title TEXT, path TEXT)`)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	err = filepath.Walk("videos", func(path string, info os.FileInfo, err error) error {
+// indexVideos walks the videos/ tree and registers any .m3u8 file that
+// isn't already in the store, so files added out-of-band are picked up
+// without a restart.
+func indexVideos(store Store, root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -61,9 +52,13 @@ func InitDB() *sql.DB {
 			title := strings.TrimSuffix(info.Name(), ".m3u8")
 			normalizedPath := filepath.ToSlash(path)
 
-			if !FileExistsByTitle(db, title) {
-				_, err := db.Exec("INSERT INTO videos (title, path) VALUES (?, ?)", title, normalizedPath)
-				if err != nil {
+			exists, err := store.ExistsByTitle(title)
+			if err != nil {
+				return err
+			}
+
+			if !exists {
+				if err := store.InsertVideo(title, normalizedPath); err != nil {
 					log.Printf("Error inserting data: %v", err)
 				} else {
 					log.Printf("Added %s to database", title)
@@ -74,24 +69,9 @@ func InitDB() *sql.DB {
 		}
 		return nil
 	})
-
-	if err != nil {
-		log.Fatalf("Error walking through video directory: %v", err)
-	}
-
-	return db
-}
-
-func FileExistsByTitle(db *sql.DB, title string) bool {
-	var exists bool
-	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM videos WHERE title = ?)", title).Scan(&exists)
-	if err != nil {
-		log.Fatalf("Error checking if title exists: %v", err)
-	}
-	return exists
 }
 
-func videoLinkHandler(db *sql.DB) http.HandlerFunc {
+func videoLinkHandler(store Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not supported", http.StatusMethodNotAllowed)
@@ -111,16 +91,14 @@ func videoLinkHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		var videoPath string
-		err = db.QueryRow("SELECT path FROM videos WHERE title = ?", req.Title).Scan(&videoPath)
+		videoPath, err := store.GetPathByTitle(req.Title)
 		if err != nil {
-			if err == sql.ErrNoRows {
+			if err == ErrNotFound {
 				http.Error(w, "Video not found", http.StatusNotFound)
 				return
-			} else {
-				http.Error(w, "Database error", http.StatusInternalServerError)
-				return
 			}
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
 		}
 
 		trimmedVideoPath := strings.TrimPrefix(videoPath, "videos/")
@@ -132,7 +110,7 @@ func videoLinkHandler(db *sql.DB) http.HandlerFunc {
 	}
 }
 
-func downloadHandler(db *sql.DB) http.HandlerFunc {
+func downloadHandler(store Store, concurrency int) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not supported", http.StatusMethodNotAllowed)
@@ -168,9 +146,13 @@ func downloadHandler(db *sql.DB) http.HandlerFunc {
 		path := fmt.Sprintf("videos/%s/%s", name, filename)
 		fmt.Println(path)
 
-		if !FileExistsByTitle(db, name) {
-			_, err := db.Exec("INSERT INTO videos (title, path) VALUES (?, ?)", name, path)
-			if err != nil {
+		exists, err := store.ExistsByTitle(name)
+		if err != nil {
+			fmt.Printf("Error checking if title exists: %v\n", err)
+			return
+		}
+		if !exists {
+			if err := store.InsertVideo(name, path); err != nil {
 				log.Printf("Error inserting data: %v", err)
 			} else {
 				log.Printf("Added %s to database", name)
@@ -216,32 +198,37 @@ func downloadHandler(db *sql.DB) http.HandlerFunc {
 
 		if listType == m3u8.MEDIA {
 			mediaPlaylist := playlist.(*m3u8.MediaPlaylist)
+			var jobs []segmentJob
 			for _, segment := range mediaPlaylist.Segments {
-				if segment != nil && segment.URI != "" {
-					output = filepath.Join(dir, segment.URI)
-					segmenturl := fmt.Sprintf("%s/%s/%s", baseURL, name, segment.URI)
-					resp, err := http.Get(segmenturl)
-					if err != nil {
-						fmt.Printf("Error downloading file: %v\n", err)
-						return
-					}
-					defer resp.Body.Close()
-
-					file, err := os.Create(output)
-					if err != nil {
-						fmt.Printf("Error creating output file: %v\n", err)
-						return
-					}
-					defer file.Close()
-
-					_, err = io.Copy(file, resp.Body)
-					if err != nil {
-						fmt.Printf("Error writing to file: %v\n", err)
-						return
-					}
-
-					fmt.Printf("File segment downloaded and saved to %s\n", output)
+				if segment == nil || segment.URI == "" {
+					continue
 				}
+
+				segmentURI, err := sanitizeRelativePath(segment.URI)
+				if err != nil {
+					fmt.Printf("Skipping segment with unsafe URI for %s: %v\n", name, err)
+					continue
+				}
+
+				jobs = append(jobs, segmentJob{
+					URL:    fmt.Sprintf("%s/%s/%s", baseURL, name, segment.URI),
+					Output: filepath.Join(dir, segmentURI),
+				})
+			}
+
+			if err := downloadSegmentsPool(jobs, concurrency, nil); err != nil {
+				fmt.Printf("Error downloading segments: %v\n", err)
+				return
+			}
+		} else if listType == m3u8.MASTER {
+			masterPlaylist := playlist.(*m3u8.MasterPlaylist)
+			if err := store.DeleteVariantsByTitle(name); err != nil {
+				fmt.Printf("Error clearing existing variants for %s: %v\n", name, err)
+				return
+			}
+			if err := fetchMasterVariants(store, masterPlaylist, dir, baseURL, name, concurrency); err != nil {
+				fmt.Printf("Error fetching master playlist variants: %v\n", err)
+				return
 			}
 		}
 
@@ -250,29 +237,18 @@ func downloadHandler(db *sql.DB) http.HandlerFunc {
 	}
 }
 
-func getAllVideosHandler(db *sql.DB) http.HandlerFunc {
+func getAllVideosHandler(store Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			http.Error(w, "Method not supported", http.StatusMethodNotAllowed)
 			return
 		}
 
-		rows, err := db.Query("SELECT title FROM videos")
+		titles, err := store.ListTitles()
 		if err != nil {
 			http.Error(w, "Database query error", http.StatusInternalServerError)
 			return
 		}
-		defer rows.Close()
-
-		var titles []string
-		for rows.Next() {
-			var title string
-			if err := rows.Scan(&title); err != nil {
-				http.Error(w, "Error scanning video data", http.StatusInternalServerError)
-				return
-			}
-			titles = append(titles, title)
-		}
 
 		response := VideoTitleResponse{
 			Titles: titles,
@@ -293,15 +269,35 @@ func addHeaders(h http.Handler) http.HandlerFunc {
 func main() {
 	var port int
 	flag.IntVar(&port, "port", 8080, "Port number")
+	adminUser := flag.String("admin-user", os.Getenv("ADMIN_USER"), "Username for /admin endpoints")
+	adminPass := flag.String("admin-pass", os.Getenv("ADMIN_PASS"), "Password for /admin endpoints")
+	var concurrency int
+	flag.IntVar(&concurrency, "concurrency", defaultSegmentConcurrency, "Number of segments to download concurrently")
+	storeKind := flag.String("store", "sqlite", "Storage backend: sqlite or postgres")
+	dsn := flag.String("dsn", os.Getenv("DATABASE_URL"), "Data source name for the selected store (defaults to $DATABASE_URL)")
 	flag.Parse()
 	const dir = "videos"
 
-	db := InitDB()
-	defer db.Close()
+	store, err := NewStore(*storeKind, *dsn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+
+	if err := indexVideos(store, dir); err != nil {
+		log.Fatalf("Error walking through video directory: %v", err)
+	}
+
 	http.Handle("/", addHeaders(http.FileServer(http.Dir(dir))))
-	http.HandleFunc("/video", videoLinkHandler(db))
-	http.HandleFunc("/download", downloadHandler(db))
-	http.HandleFunc("/videos", getAllVideosHandler(db))
+	http.HandleFunc("/video", videoLinkHandler(store))
+	http.HandleFunc("/download", downloadHandler(store, concurrency))
+	http.HandleFunc("/videos", getAllVideosHandler(store))
+	http.HandleFunc("/video/init", initUploadHandler(store))
+	http.HandleFunc("/video/chunk", chunkUploadHandler(store))
+	http.HandleFunc("/video/complete", completeUploadHandler(store))
+
+	admin := NewAdminAPI(store, *adminUser, *adminPass)
+	http.HandleFunc("/admin/videos/", admin.basicAuth(admin.videosHandler()))
 
 	fmt.Printf("Starting server on %v\n", port)
 	log.Printf("Serving %s on HTTP port: %v\n", dir, port)
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// sanitizePathComponent rejects any string that isn't safe to use as a
+// single path element — empty, ".", "..", or anything containing a
+// separator — so identifiers coming from request bodies, headers, or a
+// remote-fetched playlist can't be used to escape the directory they're
+// joined into.
+func sanitizePathComponent(s string) (string, error) {
+	if s == "" || s == "." || s == ".." {
+		return "", fmt.Errorf("invalid path component %q", s)
+	}
+	if filepath.Base(s) != s {
+		return "", fmt.Errorf("invalid path component %q", s)
+	}
+	return s, nil
+}
+
+// sanitizeRelativePath validates a relative path made of one or more
+// components — e.g. a playlist segment URI like "audio/seg0.ts" — and
+// rejects anything that could escape the directory it's joined into:
+// absolute paths and any path that still starts with ".." once cleaned
+// (so "a/../b" is accepted and returned as "b"). Unlike
+// sanitizePathComponent it allows separators, since segment URIs are
+// legitimately nested.
+func sanitizeRelativePath(s string) (string, error) {
+	if s == "" || filepath.IsAbs(s) {
+		return "", fmt.Errorf("invalid relative path %q", s)
+	}
+	cleaned := filepath.Clean(s)
+	if cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid relative path %q", s)
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultSegmentConcurrency = 8
+	segmentMaxAttempts        = 3
+	segmentRequestTimeout     = 30 * time.Second
+)
+
+var segmentHTTPClient = &http.Client{
+	Timeout: segmentRequestTimeout,
+	Transport: &http.Transport{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 100,
+		IdleConnTimeout:     90 * time.Second,
+	},
+}
+
+type segmentJob struct {
+	URL    string
+	Output string
+}
+
+// downloadSegmentsPool fetches every job through a bounded worker pool
+// instead of sequentially, retrying transient failures with exponential
+// backoff and jitter. Progress is reported every 25 completions (or via
+// progress, if non-nil) so replicating a long stream doesn't look hung.
+func downloadSegmentsPool(jobs []segmentJob, concurrency int, progress func(done, total int)) error {
+	if concurrency <= 0 {
+		concurrency = defaultSegmentConcurrency
+	}
+
+	total := len(jobs)
+	jobCh := make(chan segmentJob)
+	var completed, failed int64
+	var wg sync.WaitGroup
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobCh {
+				if err := fetchSegmentWithRetry(job); err != nil {
+					atomic.AddInt64(&failed, 1)
+					log.Printf("Error downloading segment %s after retries: %v", job.URL, err)
+					continue
+				}
+
+				done := atomic.AddInt64(&completed, 1)
+				if progress != nil {
+					progress(int(done), total)
+				} else if done%25 == 0 || int(done) == total {
+					log.Printf("Downloaded %d/%d segments", done, total)
+				}
+			}
+		}()
+	}
+
+	for _, job := range jobs {
+		jobCh <- job
+	}
+	close(jobCh)
+	wg.Wait()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d segments failed to download", failed, total)
+	}
+	return nil
+}
+
+func fetchSegmentWithRetry(job segmentJob) error {
+	var lastErr error
+	for attempt := 0; attempt < segmentMaxAttempts; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(1<<uint(attempt-1)) * 500 * time.Millisecond
+			jitter := time.Duration(rand.Int63n(int64(backoff)))
+			time.Sleep(backoff + jitter)
+		}
+
+		if err := fetchSegment(job); err != nil {
+			lastErr = err
+			continue
+		}
+		return nil
+	}
+	return lastErr
+}
+
+func fetchSegment(job segmentJob) error {
+	ctx, cancel := context.WithTimeout(context.Background(), segmentRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, job.URL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := segmentHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	file, err := os.Create(job.Output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, resp.Body)
+	return err
+}
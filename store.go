@@ -0,0 +1,46 @@
+package main
+
+import "errors"
+
+// ErrNotFound is returned by Store lookups that find no matching row,
+// independent of which backend is in use.
+var ErrNotFound = errors.New("not found")
+
+// Store is the catalog backing every handler in this service. Having
+// replicas point at the same Store implementation (e.g. Postgres) is
+// what lets them share one catalog instead of each keeping a private
+// SQLite file.
+type Store interface {
+	InsertVideo(title, path string) error
+	GetPathByTitle(title string) (string, error)
+	ListTitles() ([]string, error)
+	ExistsByTitle(title string) (bool, error)
+	DeleteByTitle(title string) error
+	RenameVideo(oldTitle, newTitle, newPath string) error
+
+	InsertVariant(title string, bandwidth uint32, resolution, path string) error
+	DeleteVariantsByTitle(title string) error
+	RenameVariantsByTitle(oldTitle, newTitle, oldPathPrefix, newPathPrefix string) error
+
+	CreateUpload(uploadID, title, fileName, dir string) error
+	GetUpload(uploadID string) (title, fileName, dir string, err error)
+	DeleteUpload(uploadID string) error
+
+	Close() error
+}
+
+// NewStore builds the Store selected by -store, using dsn as its data
+// source name (a file path for sqlite, a connection string for postgres).
+func NewStore(kind, dsn string) (Store, error) {
+	switch kind {
+	case "sqlite":
+		if dsn == "" {
+			dsn = "videos.db"
+		}
+		return NewSQLiteStore(dsn)
+	case "postgres":
+		return NewPostgresStore(dsn)
+	default:
+		return nil, errors.New("unknown store: " + kind)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresStore is the multi-replica catalog: every replica opens the
+// same DSN, so a video added by one node's /download or admin mutation
+// is immediately visible to the others.
+type PostgresStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresStore(dsn string) (*PostgresStore, error) {
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := []string{
+		`CREATE TABLE IF NOT EXISTS videos (id serial PRIMARY KEY, title text UNIQUE, path text)`,
+		`CREATE TABLE IF NOT EXISTS uploads (upload_id text PRIMARY KEY, title text, file_name text, dir text)`,
+		`CREATE TABLE IF NOT EXISTS variants (id serial PRIMARY KEY, title text, bandwidth integer, resolution text, path text)`,
+	}
+	for _, stmt := range schema {
+		if _, err := pool.Exec(context.Background(), stmt); err != nil {
+			pool.Close()
+			return nil, err
+		}
+	}
+
+	return &PostgresStore{pool: pool}, nil
+}
+
+func (s *PostgresStore) InsertVideo(title, path string) error {
+	_, err := s.pool.Exec(context.Background(), "INSERT INTO videos (title, path) VALUES ($1, $2)", title, path)
+	return err
+}
+
+func (s *PostgresStore) GetPathByTitle(title string) (string, error) {
+	var path string
+	err := s.pool.QueryRow(context.Background(), "SELECT path FROM videos WHERE title = $1", title).Scan(&path)
+	if err == pgx.ErrNoRows {
+		return "", ErrNotFound
+	}
+	return path, err
+}
+
+func (s *PostgresStore) ListTitles() ([]string, error) {
+	rows, err := s.pool.Query(context.Background(), "SELECT title FROM videos")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var titles []string
+	for rows.Next() {
+		var title string
+		if err := rows.Scan(&title); err != nil {
+			return nil, err
+		}
+		titles = append(titles, title)
+	}
+	return titles, rows.Err()
+}
+
+func (s *PostgresStore) ExistsByTitle(title string) (bool, error) {
+	var exists bool
+	err := s.pool.QueryRow(context.Background(), "SELECT EXISTS(SELECT 1 FROM videos WHERE title = $1)", title).Scan(&exists)
+	return exists, err
+}
+
+func (s *PostgresStore) DeleteByTitle(title string) error {
+	_, err := s.pool.Exec(context.Background(), "DELETE FROM videos WHERE title = $1", title)
+	return err
+}
+
+func (s *PostgresStore) RenameVideo(oldTitle, newTitle, newPath string) error {
+	_, err := s.pool.Exec(context.Background(), "UPDATE videos SET title = $1, path = $2 WHERE title = $3",
+		newTitle, newPath, oldTitle)
+	return err
+}
+
+func (s *PostgresStore) InsertVariant(title string, bandwidth uint32, resolution, path string) error {
+	_, err := s.pool.Exec(context.Background(),
+		"INSERT INTO variants (title, bandwidth, resolution, path) VALUES ($1, $2, $3, $4)",
+		title, bandwidth, resolution, path)
+	return err
+}
+
+func (s *PostgresStore) DeleteVariantsByTitle(title string) error {
+	_, err := s.pool.Exec(context.Background(), "DELETE FROM variants WHERE title = $1", title)
+	return err
+}
+
+func (s *PostgresStore) RenameVariantsByTitle(oldTitle, newTitle, oldPathPrefix, newPathPrefix string) error {
+	_, err := s.pool.Exec(context.Background(),
+		"UPDATE variants SET title = $1, path = REPLACE(path, $2, $3) WHERE title = $4",
+		newTitle, oldPathPrefix, newPathPrefix, oldTitle)
+	return err
+}
+
+func (s *PostgresStore) CreateUpload(uploadID, title, fileName, dir string) error {
+	_, err := s.pool.Exec(context.Background(),
+		"INSERT INTO uploads (upload_id, title, file_name, dir) VALUES ($1, $2, $3, $4)",
+		uploadID, title, fileName, dir)
+	return err
+}
+
+func (s *PostgresStore) GetUpload(uploadID string) (title, fileName, dir string, err error) {
+	err = s.pool.QueryRow(context.Background(),
+		"SELECT title, file_name, dir FROM uploads WHERE upload_id = $1", uploadID).
+		Scan(&title, &fileName, &dir)
+	if err == pgx.ErrNoRows {
+		err = ErrNotFound
+	}
+	return
+}
+
+func (s *PostgresStore) DeleteUpload(uploadID string) error {
+	_, err := s.pool.Exec(context.Background(), "DELETE FROM uploads WHERE upload_id = $1", uploadID)
+	return err
+}
+
+func (s *PostgresStore) Close() error {
+	s.pool.Close()
+	return nil
+}
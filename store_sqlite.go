@@ -0,0 +1,123 @@
+package main
+
+import (
+	"database/sql"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteStore is the original single-node catalog: a sqlite3 database
+// file, typically videos.db in the working directory.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteStore(dsn string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := []string{
+		`CREATE TABLE IF NOT EXISTS videos (title TEXT, path TEXT)`,
+		`CREATE TABLE IF NOT EXISTS uploads (upload_id TEXT PRIMARY KEY, title TEXT, file_name TEXT, dir TEXT)`,
+		`CREATE TABLE IF NOT EXISTS variants (title TEXT, bandwidth INTEGER, resolution TEXT, path TEXT)`,
+	}
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) InsertVideo(title, path string) error {
+	_, err := s.db.Exec("INSERT INTO videos (title, path) VALUES (?, ?)", title, path)
+	return err
+}
+
+func (s *SQLiteStore) GetPathByTitle(title string) (string, error) {
+	var path string
+	err := s.db.QueryRow("SELECT path FROM videos WHERE title = ?", title).Scan(&path)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	return path, err
+}
+
+func (s *SQLiteStore) ListTitles() ([]string, error) {
+	rows, err := s.db.Query("SELECT title FROM videos")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var titles []string
+	for rows.Next() {
+		var title string
+		if err := rows.Scan(&title); err != nil {
+			return nil, err
+		}
+		titles = append(titles, title)
+	}
+	return titles, rows.Err()
+}
+
+func (s *SQLiteStore) ExistsByTitle(title string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM videos WHERE title = ?)", title).Scan(&exists)
+	return exists, err
+}
+
+func (s *SQLiteStore) DeleteByTitle(title string) error {
+	_, err := s.db.Exec("DELETE FROM videos WHERE title = ?", title)
+	return err
+}
+
+func (s *SQLiteStore) RenameVideo(oldTitle, newTitle, newPath string) error {
+	_, err := s.db.Exec("UPDATE videos SET title = ?, path = ? WHERE title = ?", newTitle, newPath, oldTitle)
+	return err
+}
+
+func (s *SQLiteStore) InsertVariant(title string, bandwidth uint32, resolution, path string) error {
+	_, err := s.db.Exec("INSERT INTO variants (title, bandwidth, resolution, path) VALUES (?, ?, ?, ?)",
+		title, bandwidth, resolution, path)
+	return err
+}
+
+func (s *SQLiteStore) DeleteVariantsByTitle(title string) error {
+	_, err := s.db.Exec("DELETE FROM variants WHERE title = ?", title)
+	return err
+}
+
+func (s *SQLiteStore) RenameVariantsByTitle(oldTitle, newTitle, oldPathPrefix, newPathPrefix string) error {
+	_, err := s.db.Exec("UPDATE variants SET title = ?, path = REPLACE(path, ?, ?) WHERE title = ?",
+		newTitle, oldPathPrefix, newPathPrefix, oldTitle)
+	return err
+}
+
+func (s *SQLiteStore) CreateUpload(uploadID, title, fileName, dir string) error {
+	_, err := s.db.Exec("INSERT INTO uploads (upload_id, title, file_name, dir) VALUES (?, ?, ?, ?)",
+		uploadID, title, fileName, dir)
+	return err
+}
+
+func (s *SQLiteStore) GetUpload(uploadID string) (title, fileName, dir string, err error) {
+	err = s.db.QueryRow("SELECT title, file_name, dir FROM uploads WHERE upload_id = ?", uploadID).
+		Scan(&title, &fileName, &dir)
+	if err == sql.ErrNoRows {
+		err = ErrNotFound
+	}
+	return
+}
+
+func (s *SQLiteStore) DeleteUpload(uploadID string) error {
+	_, err := s.db.Exec("DELETE FROM uploads WHERE upload_id = ?", uploadID)
+	return err
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grafov/m3u8"
+)
+
+type InitUploadRequest struct {
+	Title    string `json:"title"`
+	FileName string `json:"filename"`
+}
+
+type InitUploadResponse struct {
+	UploadID string `json:"upload_id"`
+}
+
+type CompleteUploadRequest struct {
+	UploadID string `json:"upload_id"`
+	BaseURL  string `json:"base_url,omitempty"`
+}
+
+func uploadStagingDir(uploadID string) string {
+	return filepath.Join("videos-staging", uploadID)
+}
+
+func generateUploadID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// initUploadHandler reserves a staging directory for a new upload and
+// returns an upload_id the client attaches to subsequent chunk requests.
+func initUploadHandler(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req InitUploadRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if req.Title == "" || req.FileName == "" {
+			http.Error(w, "Missing title or filename in request body", http.StatusBadRequest)
+			return
+		}
+
+		title, err := sanitizePathComponent(req.Title)
+		if err != nil {
+			http.Error(w, "Invalid title", http.StatusBadRequest)
+			return
+		}
+		fileName, err := sanitizePathComponent(req.FileName)
+		if err != nil {
+			http.Error(w, "Invalid filename", http.StatusBadRequest)
+			return
+		}
+
+		exists, err := store.ExistsByTitle(title)
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if exists {
+			http.Error(w, "A video with this title already exists", http.StatusConflict)
+			return
+		}
+
+		uploadID, err := generateUploadID()
+		if err != nil {
+			http.Error(w, "Error generating upload id", http.StatusInternalServerError)
+			return
+		}
+
+		dir := uploadStagingDir(uploadID)
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			http.Error(w, "Error creating staging directory", http.StatusInternalServerError)
+			return
+		}
+
+		if err := store.CreateUpload(uploadID, title, fileName, dir); err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(InitUploadResponse{UploadID: uploadID})
+	}
+}
+
+// chunkUploadHandler stores a single chunk's raw bytes under the upload's
+// staging directory, keyed by Chunk-Id so chunks can arrive out of order
+// and a dropped connection only requires re-sending the missing ones.
+func chunkUploadHandler(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		uploadID := r.Header.Get("Upload-Id")
+		chunkIDHeader := r.Header.Get("Chunk-Id")
+		fileName := r.Header.Get("File-Name")
+		if uploadID == "" || chunkIDHeader == "" {
+			http.Error(w, "Missing Upload-Id or Chunk-Id header", http.StatusBadRequest)
+			return
+		}
+
+		chunkIndex, err := strconv.Atoi(chunkIDHeader)
+		if err != nil || chunkIndex < 0 {
+			http.Error(w, "Chunk-Id must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+
+		_, storedFileName, dir, err := store.GetUpload(uploadID)
+		if err != nil {
+			if err == ErrNotFound {
+				http.Error(w, "Unknown upload id", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+
+		if fileName != "" && fileName != storedFileName {
+			http.Error(w, "File-Name does not match the upload", http.StatusBadRequest)
+			return
+		}
+
+		defer r.Body.Close()
+
+		chunkPath := filepath.Join(dir, strconv.Itoa(chunkIndex))
+		f, err := os.Create(chunkPath)
+		if err != nil {
+			http.Error(w, "Error storing chunk", http.StatusInternalServerError)
+			return
+		}
+		defer f.Close()
+
+		if _, err := io.Copy(f, r.Body); err != nil {
+			http.Error(w, "Error writing chunk", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// completeUploadHandler reassembles chunks in order, runs the m3u8 parser
+// over the result if it looks like a playlist, registers the video, and
+// clears the staging state.
+func completeUploadHandler(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req CompleteUploadRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if req.UploadID == "" {
+			http.Error(w, "Missing upload_id in request body", http.StatusBadRequest)
+			return
+		}
+
+		title, fileName, dir, err := store.GetUpload(req.UploadID)
+		if err != nil {
+			if err == ErrNotFound {
+				http.Error(w, "Unknown upload id", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			http.Error(w, "Error reading staged chunks", http.StatusInternalServerError)
+			return
+		}
+
+		chunkIDs := make([]int, 0, len(entries))
+		for _, entry := range entries {
+			id, err := strconv.Atoi(entry.Name())
+			if err != nil {
+				continue
+			}
+			chunkIDs = append(chunkIDs, id)
+		}
+		sort.Ints(chunkIDs)
+
+		outputDir := filepath.Join("videos", title)
+		if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+			http.Error(w, "Error creating video directory", http.StatusInternalServerError)
+			return
+		}
+		outputPath := filepath.Join(outputDir, fileName)
+
+		out, err := os.Create(outputPath)
+		if err != nil {
+			http.Error(w, "Error creating output file", http.StatusInternalServerError)
+			return
+		}
+
+		for _, id := range chunkIDs {
+			chunkPath := filepath.Join(dir, strconv.Itoa(id))
+			chunk, err := os.Open(chunkPath)
+			if err != nil {
+				out.Close()
+				http.Error(w, "Error reading chunk", http.StatusInternalServerError)
+				return
+			}
+			_, err = io.Copy(out, chunk)
+			chunk.Close()
+			if err != nil {
+				out.Close()
+				http.Error(w, "Error assembling file", http.StatusInternalServerError)
+				return
+			}
+		}
+		out.Close()
+
+		if filepath.Ext(fileName) == ".m3u8" {
+			if err := fetchPlaylistSegments(outputPath, outputDir, req.BaseURL, title); err != nil {
+				log.Printf("Error fetching playlist segments for %s: %v", title, err)
+			}
+		}
+
+		relativePath := filepath.ToSlash(outputPath)
+		exists, err := store.ExistsByTitle(title)
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if !exists {
+			if err := store.InsertVideo(title, relativePath); err != nil {
+				http.Error(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := store.DeleteUpload(req.UploadID); err != nil {
+			log.Printf("Error clearing upload state for %s: %v", req.UploadID, err)
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			log.Printf("Error removing staging directory %s: %v", dir, err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Video{Title: title, Path: relativePath})
+	}
+}
+
+// fetchPlaylistSegments decodes a media playlist just written to disk and
+// downloads any referenced segments that aren't already present, using
+// baseURL to resolve segment URIs the same way downloadHandler does.
+func fetchPlaylistSegments(playlistPath, dir, baseURL, title string) error {
+	if baseURL == "" {
+		return nil
+	}
+
+	content, err := ioutil.ReadFile(playlistPath)
+	if err != nil {
+		return fmt.Errorf("reading playlist: %w", err)
+	}
+
+	playlist, listType, err := m3u8.DecodeFrom(bufio.NewReader(strings.NewReader(string(content))), true)
+	if err != nil {
+		return fmt.Errorf("decoding playlist: %w", err)
+	}
+
+	if listType != m3u8.MEDIA {
+		return nil
+	}
+
+	mediaPlaylist := playlist.(*m3u8.MediaPlaylist)
+	var jobs []segmentJob
+	for _, segment := range mediaPlaylist.Segments {
+		if segment == nil || segment.URI == "" {
+			continue
+		}
+
+		segmentURI, err := sanitizeRelativePath(segment.URI)
+		if err != nil {
+			log.Printf("Skipping segment with unsafe URI for %s: %v", title, err)
+			continue
+		}
+
+		segmentPath := filepath.Join(dir, segmentURI)
+		if _, err := os.Stat(segmentPath); err == nil {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(segmentPath), os.ModePerm); err != nil {
+			return err
+		}
+
+		jobs = append(jobs, segmentJob{
+			URL:    fmt.Sprintf("%s/%s/%s", baseURL, title, segment.URI),
+			Output: segmentPath,
+		})
+	}
+
+	return downloadSegmentsPool(jobs, defaultSegmentConcurrency, nil)
+}